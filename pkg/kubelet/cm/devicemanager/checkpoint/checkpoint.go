@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager"
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager/checksum"
+	cmerrors "k8s.io/kubernetes/pkg/kubelet/checkpointmanager/errors"
+)
+
+// DevicesPerNUMA represents device ids obtained from device plugin per NUMA node id
+type DevicesPerNUMA map[int64][]string
+
+// DeviceManagerCheckpoint defines the operations to retrieve pod devices
+type DeviceManagerCheckpoint interface {
+	checkpointmanager.Checkpoint
+	GetDataInLatestFormat() ([]PodDevicesEntry, map[string][]string)
+}
+
+// PodDevicesEntry connects pod information to devices
+type PodDevicesEntry struct {
+	PodUID        string
+	ContainerName string
+	ResourceName  string
+	DeviceIDs     DevicesPerNUMA
+	AllocResp     []byte
+}
+
+// checkpointData struct is used to store pod to device allocation information
+// in a checkpoint file in the latest, topology-aware format
+type checkpointData struct {
+	PodDeviceEntries  []PodDevicesEntry
+	RegisteredDevices map[string][]string
+}
+
+// Data holds checkpoint data and its checksum
+type Data struct {
+	Data     checkpointData
+	Checksum checksum.Checksum
+}
+
+// New returns an instance of Checkpoint, which can be used for
+// storing and restoring data in the latest format
+func New(devEntries []PodDevicesEntry, devices map[string][]string) DeviceManagerCheckpoint {
+	return &Data{
+		Data: checkpointData{
+			PodDeviceEntries:  devEntries,
+			RegisteredDevices: devices,
+		},
+	}
+}
+
+// MarshalCheckpoint returns marshalled checkpoint
+func (cp *Data) MarshalCheckpoint() ([]byte, error) {
+	cp.Checksum = checksum.New(cp.Data)
+	return json.Marshal(*cp)
+}
+
+// UnmarshalCheckpoint returns unmarshalled checkpoint data
+func (cp *Data) UnmarshalCheckpoint(blob []byte) error {
+	return json.Unmarshal(blob, cp)
+}
+
+// VerifyChecksum verifies that the current checksum of the checkpoint data is valid
+func (cp *Data) VerifyChecksum() error {
+	ck := cp.Checksum
+	cp.Checksum = 0
+	err := ck.Verify(cp.Data)
+	cp.Checksum = ck
+	return err
+}
+
+// GetDataInLatestFormat returns device entries and registered device maps
+// in the latest checkpoint format
+func (cp *Data) GetDataInLatestFormat() ([]PodDevicesEntry, map[string][]string) {
+	return cp.Data.PodDeviceEntries, cp.Data.RegisteredDevices
+}
+
+// IsParseError reports whether err indicates the checkpoint payload itself could not be
+// parsed or trusted (truncated/invalid JSON, a checksum mismatch, or an unrecognized version),
+// as opposed to e.g. the checkpoint file simply not existing yet. Callers use this to decide
+// whether a checkpoint needs to be quarantined rather than just recreated from scratch.
+func IsParseError(err error) bool {
+	if err == nil || errors.Is(err, cmerrors.ErrCheckpointNotFound) {
+		return false
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return true
+	}
+	return errors.Is(err, cmerrors.ErrCorruptCheckpoint)
+}
+
+// Quarantine moves the checkpoint file at dir/name out of the way, so a checkpoint that
+// repeatedly fails to parse doesn't keep the device manager from starting up on every kubelet
+// restart. The file is renamed to "<name>.bad-<unix-timestamp>" alongside the original, and the
+// new path is returned so callers can log it.
+func Quarantine(dir, name string, now time.Time) (string, error) {
+	src := filepath.Join(dir, name)
+	dst := filepath.Join(dir, fmt.Sprintf("%s.bad-%d", name, now.Unix()))
+	if err := os.Rename(src, dst); err != nil {
+		return "", fmt.Errorf("failed to quarantine corrupted checkpoint %q: %w", src, err)
+	}
+	return dst, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"encoding/json"
+
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager/checksum"
+)
+
+// NodeWithoutTopology is the NUMA node id used for devices that predate topology-aware
+// checkpoints (i.e. devices recovered from a V1 checkpoint).
+const NodeWithoutTopology = -1
+
+// PodDevicesEntryV1 connects pod information to devices, pre-dating NUMA topology awareness
+type PodDevicesEntryV1 struct {
+	PodUID        string
+	ContainerName string
+	ResourceName  string
+	DeviceIDs     []string
+	AllocResp     []byte
+}
+
+// checkpointDataV1 struct is used to store pod to device allocation information
+// in a checkpoint file, in the V1 (pre-1.20) format
+type checkpointDataV1 struct {
+	PodDeviceEntries  []PodDevicesEntryV1
+	RegisteredDevices map[string][]string
+}
+
+// DataV1 holds checkpoint data and its checksum, in the V1 format
+type DataV1 struct {
+	Data     checkpointDataV1
+	Checksum checksum.Checksum
+}
+
+// NewV1 returns an instance of Checkpoint, which can be used for
+// storing and restoring data in the V1 format
+func NewV1(devEntries []PodDevicesEntryV1, devices map[string][]string) DeviceManagerCheckpoint {
+	return &DataV1{
+		Data: checkpointDataV1{
+			PodDeviceEntries:  devEntries,
+			RegisteredDevices: devices,
+		},
+	}
+}
+
+// MarshalCheckpoint returns marshalled checkpoint
+func (cp *DataV1) MarshalCheckpoint() ([]byte, error) {
+	cp.Checksum = checksum.New(cp.Data)
+	return json.Marshal(*cp)
+}
+
+// UnmarshalCheckpoint returns unmarshalled checkpoint data
+func (cp *DataV1) UnmarshalCheckpoint(blob []byte) error {
+	return json.Unmarshal(blob, cp)
+}
+
+// VerifyChecksum verifies that the current checksum of the checkpoint data is valid
+func (cp *DataV1) VerifyChecksum() error {
+	ck := cp.Checksum
+	cp.Checksum = 0
+	err := ck.Verify(cp.Data)
+	cp.Checksum = ck
+	return err
+}
+
+// GetDataInLatestFormat upconverts the V1 pod device entries into the latest, NUMA-aware
+// format. Devices recovered from a V1 checkpoint carry no topology information, so they are
+// tagged with NodeWithoutTopology until the device plugin re-registers and the device manager
+// rewrites the checkpoint with real NUMA data.
+func (cp *DataV1) GetDataInLatestFormat() ([]PodDevicesEntry, map[string][]string) {
+	var podDevs []PodDevicesEntry
+	for _, entry := range cp.Data.PodDeviceEntries {
+		podDevs = append(podDevs, PodDevicesEntry{
+			PodUID:        entry.PodUID,
+			ContainerName: entry.ContainerName,
+			ResourceName:  entry.ResourceName,
+			DeviceIDs:     DevicesPerNUMA{NodeWithoutTopology: entry.DeviceIDs},
+			AllocResp:     entry.AllocResp,
+		})
+	}
+	return podDevs, cp.Data.RegisteredDevices
+}
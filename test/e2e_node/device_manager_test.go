@@ -28,8 +28,10 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
 	kubeletpodresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
@@ -38,6 +40,7 @@ import (
 	"k8s.io/kubernetes/pkg/kubelet/cm/devicemanager/checkpoint"
 	"k8s.io/kubernetes/pkg/kubelet/util"
 	admissionapi "k8s.io/pod-security-admission/api"
+	"k8s.io/utils/ptr"
 
 	"k8s.io/kubernetes/test/e2e/framework"
 	e2enode "k8s.io/kubernetes/test/e2e/framework/node"
@@ -271,6 +274,78 @@ var _ = SIGDescribe("Device Manager  [Serial] [Feature:DeviceManager][NodeFeatur
 			}
 		})
 
+		ginkgo.It("should rewrite a V1 (aka pre-1.20) checkpoint in the latest format, preserving NUMA topology, once the device plugin re-registers", func(ctx context.Context) {
+			if sriovdevCount, err := countSRIOVDevices(); err != nil || sriovdevCount == 0 {
+				e2eskipper.Skipf("this test is meant to run on a system with at least one configured VF from SRIOV device")
+			}
+
+			configMap := getSRIOVDevicePluginConfigMap(framework.TestContext.SriovdpConfigMapFile)
+			sd := setupSRIOVConfigOrFail(ctx, f, configMap)
+			waitForSRIOVResources(ctx, f, sd)
+
+			cntName := "gu-container"
+			ginkgo.By(fmt.Sprintf("Successfully admit one guaranteed pod with 1 core, 1 %s device", sd.resourceName))
+			var initCtnAttrs []tmCtnAttribute
+			ctnAttrs := []tmCtnAttribute{
+				{
+					ctnName:       cntName,
+					cpuRequest:    "1000m",
+					cpuLimit:      "1000m",
+					deviceName:    sd.resourceName,
+					deviceRequest: "1",
+					deviceLimit:   "1",
+				},
+			}
+
+			podName := "gu-pod-rec-v1-latest"
+			pod := makeTopologyManagerTestPod(podName, ctnAttrs, initCtnAttrs)
+			pod = e2epod.NewPodClient(f).CreateSync(ctx, pod)
+
+			ginkgo.By("deleting the pod")
+			deletePodSyncByName(ctx, f, pod.Name)
+			waitForAllContainerRemoval(ctx, pod.Name, pod.Namespace)
+
+			ginkgo.By("teardown the sriov device plugin")
+			deleteSRIOVPodOrFail(ctx, f, sd)
+
+			ginkgo.By("stopping the kubelet")
+			killKubelet("SIGSTOP")
+
+			ginkgo.By("rewriting the kubelet checkpoint file as v1")
+			err := rewriteCheckpointAsV1(devicePluginDir, checkpointName)
+			defer os.Remove(checkpointFullPath)
+			framework.ExpectNoError(err)
+
+			ginkgo.By("killing the kubelet")
+			killKubelet("SIGKILL")
+
+			ginkgo.By("waiting for the kubelet to be ready again")
+			gomega.Eventually(ctx, func(ctx context.Context) bool {
+				nodes, err := e2enode.TotalReady(ctx, f.ClientSet)
+				framework.ExpectNoError(err)
+				return nodes == 1
+			}, time.Minute, time.Second).Should(gomega.BeTrue())
+
+			sd2 := &sriovData{
+				configMap:      sd.configMap,
+				serviceAccount: sd.serviceAccount,
+			}
+			sd2.pod = createSRIOVPodOrFail(ctx, f)
+			ginkgo.DeferCleanup(teardownSRIOVConfigOrFail, f, sd2)
+			waitForSRIOVResources(ctx, f, sd2)
+
+			ginkgo.By("admitting a fresh guaranteed pod so the rewritten checkpoint records a NUMA-aware allocation")
+			podName = "gu-pod-rec-v1-latest-2"
+			pod = makeTopologyManagerTestPod(podName, ctnAttrs, initCtnAttrs)
+			pod = e2epod.NewPodClient(f).CreateSync(ctx, pod)
+			ginkgo.DeferCleanup(deletePodSyncByName, f, pod.Name)
+
+			ginkgo.By("checking the on-disk checkpoint has been rewritten in the latest format, with NUMA topology preserved")
+			gomega.Eventually(ctx, func() (bool, error) {
+				return isCheckpointInLatestFormatWithTopology(devicePluginDir, checkpointName, pod.UID)
+			}, time.Minute, time.Second).Should(gomega.BeTrue())
+		})
+
 	})
 
 	ginkgo.Context("With sample device plugin", func(ctx context.Context) {
@@ -326,14 +401,15 @@ var _ = SIGDescribe("Device Manager  [Serial] [Feature:DeviceManager][NodeFeatur
 			ginkgo.By("Deleting any Pods created by the test")
 			l, err := e2epod.NewPodClient(f).List(context.TODO(), metav1.ListOptions{})
 			framework.ExpectNoError(err)
-			for _, p := range l.Items {
+			var leftoverPods []*v1.Pod
+			for i := range l.Items {
+				p := &l.Items[i]
 				if p.Namespace != f.Namespace.Name {
 					continue
 				}
-
-				framework.Logf("Deleting pod: %s", p.Name)
-				e2epod.NewPodClient(f).DeleteSync(ctx, p.Name, metav1.DeleteOptions{}, 2*time.Minute)
+				leftoverPods = append(leftoverPods, p)
 			}
+			deleteBatchImmediate(ctx, f, leftoverPods)
 
 			restartKubelet(true)
 
@@ -460,6 +536,35 @@ func convertPodDeviceEntriesToV1(entries []checkpoint.PodDevicesEntry) []checkpo
 	return entriesv1
 }
 
+// isCheckpointInLatestFormatWithTopology reads the on-disk checkpoint and reports whether any
+// entry for podUID is present and still distinguishes its devices by NUMA node, which is only
+// true once the checkpoint has actually been rewritten from V1 into the latest format. A pod can
+// have more than one PodDevicesEntry (one per container/resource), so every matching entry is
+// scanned before concluding the pod's allocation carries no real topology.
+func isCheckpointInLatestFormatWithTopology(dir, name string, podUID types.UID) (bool, error) {
+	checkpointManager, err := checkpointmanager.NewCheckpointManager(dir)
+	if err != nil {
+		return false, err
+	}
+	cp := checkpoint.New(make([]checkpoint.PodDevicesEntry, 0), make(map[string][]string))
+	if err := checkpointManager.GetCheckpoint(name, cp); err != nil {
+		return false, err
+	}
+
+	podDevices, _ := cp.GetDataInLatestFormat()
+	for _, entry := range podDevices {
+		if entry.PodUID != string(podUID) {
+			continue
+		}
+		for numaNode := range entry.DeviceIDs {
+			if numaNode != checkpoint.NodeWithoutTopology {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 func stringifyContainerDevices(devs []*kubeletpodresourcesv1.ContainerDevices) string {
 	entries := []string{}
 	for _, dev := range devs {
@@ -514,6 +619,41 @@ func deleteBatch(ctx context.Context, f *framework.Framework, pods []*v1.Pod) {
 	wg.Wait()
 }
 
+// deletePodImmediateByName issues a forceful delete (no grace period) for the named pod and
+// does not wait for the corresponding API object to be gone. Callers which need to make sure
+// the containers are actually gone should follow up with waitForAllContainerRemoval.
+func deletePodImmediateByName(ctx context.Context, f *framework.Framework, podName string) {
+	framework.Logf("Immediately deleting pod %q", podName)
+	gp := int64(0)
+	policy := metav1.DeletePropagationBackground
+	err := f.ClientSet.CoreV1().Pods(f.Namespace.Name).Delete(ctx, podName, metav1.DeleteOptions{
+		GracePeriodSeconds: ptr.To(gp),
+		PropagationPolicy:  &policy,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		framework.ExpectNoError(err)
+	}
+}
+
+// deleteBatchImmediate is a fast-path variant of deleteBatch: it deletes all the given pods in
+// parallel with no grace period, then waits only for the CRI-level container removal. It does
+// not wait for the graceful pod shutdown sequence, so it must only be used by tests that don't
+// care about graceful termination (e.g. tests that already stop/restart the kubelet).
+func deleteBatchImmediate(ctx context.Context, f *framework.Framework, pods []*v1.Pod) {
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		wg.Add(1)
+		go func(podNS, podName string) {
+			defer ginkgo.GinkgoRecover()
+			defer wg.Done()
+
+			deletePodImmediateByName(ctx, f, podName)
+			waitForAllContainerRemoval(ctx, podName, podNS)
+		}(pod.Namespace, pod.Name)
+	}
+	wg.Wait()
+}
+
 func makeBusyboxDeviceRequiringPod(resourceName, cmd string) *v1.Pod {
 	podName := "device-manager-test-" + string(uuid.NewUUID())
 	rl := v1.ResourceList{